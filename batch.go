@@ -0,0 +1,233 @@
+package verkle
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/protolambda/go-kzg"
+	"github.com/protolambda/go-kzg/bls"
+)
+
+// batchBucketThreshold is the minimum number of leaves a top-level bucket
+// must contain before it is worth building as an independent subtree in
+// its own goroutine. Below this size, the overhead of spawning a
+// goroutine and committing a near-empty subtree outweighs the gain, so
+// the bucket is folded in through the regular InsertOrdered path
+// instead.
+const batchBucketThreshold = 100
+
+// batchWorkers bounds how many buckets are built concurrently, so that a
+// key set that fans out into hundreds of non-trivial top-level buckets
+// doesn't oversubscribe the machine.
+var batchWorkers = runtime.GOMAXPROCS(0)
+
+// AddBatch inserts keys and values into the tree rooted at n in a single
+// pass, exploiting the fact that the radix is fixed at
+// InternalNodeNumChildren: once the input is sorted by key, every key
+// destined for the same top-level child is contiguous. Buckets bigger
+// than batchBucketThreshold are built as standalone subtrees in parallel
+// goroutines and spliced into n.children, with only the affected root
+// commitment indices being recomputed; smaller buckets fall back to
+// InsertOrdered. If n already has a child at a touched index, the new
+// bucket is merged into the existing subtree rather than replacing it.
+//
+// keys and values are sorted in place; pass copies if the caller needs
+// to preserve the original order.
+func (n *internalNode) AddBatch(keys, values [][]byte, ks *kzg.KZGSettings, lg1 []bls.G1Point) error {
+	if len(keys) != len(values) {
+		return errors.New("verkle: AddBatch keys and values must have the same length")
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	sort.Sort(&sortableKVs{keys, values})
+
+	buckets := partitionByTopIndex(keys, values)
+
+	// Capture what each touched slot hashed to before this batch
+	// touches it, so the root commitment can be updated by folding in
+	// just the delta for each dirty index afterwards, instead of
+	// recomputing the full InternalNodeNumChildren-wide
+	// multi-exponentiation.
+	oldVals := make(map[int]bls.Fr, len(buckets))
+	for index := range buckets {
+		var old bls.Fr
+		hashChild(&old, n.children[index])
+		oldVals[index] = old
+	}
+
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, batchWorkers)
+		mu       sync.Mutex
+		dirty    = make([]int, 0, len(buckets))
+		firstErr error
+	)
+
+	for index, bucket := range buckets {
+		index, bucket := index, bucket
+
+		if len(bucket.keys) < batchBucketThreshold {
+			for i, key := range bucket.keys {
+				if err := n.InsertOrdered(key, bucket.values[i], ks, lg1); err != nil {
+					return err
+				}
+			}
+			mu.Lock()
+			dirty = append(dirty, index)
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			mu.Lock()
+			existing := n.children[index]
+			mu.Unlock()
+
+			merged, err := mergeOrBuildSubtree(n.depth+1, existing, bucket.keys, bucket.values, ks, lg1)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			n.children[index] = merged
+			dirty = append(dirty, index)
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	for _, index := range dirty {
+		old := oldVals[index]
+		var newVal bls.Fr
+		hashChild(&newVal, n.children[index])
+		applyCommitmentDelta(n, index, &old, &newVal, lg1)
+	}
+
+	return nil
+}
+
+// buildSubtree builds a standalone subtree out of a sorted, bucketed run
+// of keys that all share the same path down to depth, computing its
+// commitment independently of any sibling state so it can be spliced
+// into a parent without further coordination.
+func buildSubtree(depth byte, keys, values [][]byte, ks *kzg.KZGSettings, lg1 []bls.G1Point) (*internalNode, error) {
+	root := newInternalNode(depth, nil).(*internalNode)
+	for i, key := range keys {
+		if err := root.InsertOrdered(key, values[i], ks, lg1); err != nil {
+			return nil, err
+		}
+	}
+	root.ComputeCommitment(ks, lg1)
+	return root, nil
+}
+
+// mergeOrBuildSubtree builds the subtree that should end up at a
+// top-level slot given what's already there: a plain build if the slot
+// was empty, the bucket merged into the existing subtree if it already
+// held one, or a fresh subtree built from the bucket plus the
+// pre-existing leaf if the slot held a single leaf that the bucket's
+// keys now share a prefix with.
+func mergeOrBuildSubtree(depth byte, existing VerkleNode, keys, values [][]byte, ks *kzg.KZGSettings, lg1 []bls.G1Point) (VerkleNode, error) {
+	switch e := existing.(type) {
+	case nil:
+		return buildSubtree(depth, keys, values, ks, lg1)
+	case *internalNode:
+		for i, key := range keys {
+			if err := e.InsertOrdered(key, values[i], ks, lg1); err != nil {
+				return nil, err
+			}
+		}
+		e.ComputeCommitment(ks, lg1)
+		return e, nil
+	case *leafNode:
+		allKeys, allValues := insertSorted(keys, values, e.key, e.value)
+		return buildSubtree(depth, allKeys, allValues, ks, lg1)
+	case *hashedNode:
+		return nil, errors.New("verkle: AddBatch cannot merge into a hashed (unresolved) node")
+	default:
+		return nil, errors.New("verkle: AddBatch encountered an unknown node type")
+	}
+}
+
+// insertSorted returns keys/values with (key, value) spliced in at its
+// sorted position, preserving the sorted-input invariant InsertOrdered
+// relies on.
+func insertSorted(keys, values [][]byte, key, value []byte) ([][]byte, [][]byte) {
+	i := sort.Search(len(keys), func(i int) bool { return bytes.Compare(keys[i], key) >= 0 })
+
+	outKeys := make([][]byte, 0, len(keys)+1)
+	outValues := make([][]byte, 0, len(values)+1)
+	outKeys = append(outKeys, keys[:i]...)
+	outKeys = append(outKeys, key)
+	outKeys = append(outKeys, keys[i:]...)
+	outValues = append(outValues, values[:i]...)
+	outValues = append(outValues, value)
+	outValues = append(outValues, values[i:]...)
+	return outKeys, outValues
+}
+
+type bucket struct {
+	keys, values [][]byte
+}
+
+// partitionByTopIndex splits a sorted run of (key, value) pairs into the
+// up-to-InternalNodeNumChildren buckets addressed by each key's
+// top-level child index. Because the input is sorted, keys bound for the
+// same bucket are already contiguous, so this is a single linear pass.
+func partitionByTopIndex(keys, values [][]byte) map[int]bucket {
+	buckets := make(map[int]bucket)
+
+	start := 0
+	for start < len(keys) {
+		index := topLevelIndex(keys[start])
+		end := start + 1
+		for end < len(keys) && topLevelIndex(keys[end]) == index {
+			end++
+		}
+		buckets[index] = bucket{keys: keys[start:end], values: values[start:end]}
+		start = end
+	}
+
+	return buckets
+}
+
+// topLevelIndex returns the child index, in [0, InternalNodeNumChildren),
+// that a key is routed through at the root of the tree.
+func topLevelIndex(key []byte) int {
+	return (int(key[0]) << 2) | int(key[1]>>6)
+}
+
+// sortableKVs sorts a pair of parallel key/value slices in lockstep by
+// key, so InsertOrdered's sorted-input assumption can be satisfied
+// without allocating an index slice.
+type sortableKVs struct {
+	keys, values [][]byte
+}
+
+func (s *sortableKVs) Len() int { return len(s.keys) }
+
+func (s *sortableKVs) Less(i, j int) bool {
+	return bytes.Compare(s.keys[i], s.keys[j]) < 0
+}
+
+func (s *sortableKVs) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
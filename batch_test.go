@@ -0,0 +1,197 @@
+// This is free and unencumbered software released into the public domain.
+//
+// Anyone is free to copy, modify, publish, use, compile, sell, or
+// distribute this software, either in source code form or as a compiled
+// binary, for any purpose, commercial or non-commercial, and by any
+// means.
+//
+// In jurisdictions that recognize copyright laws, the author or authors
+// of this software dedicate any and all copyright interest in the
+// software to the public domain. We make this dedication for the benefit
+// of the public at large and to the detriment of our heirs and
+// successors. We intend this dedication to be an overt act of
+// relinquishment in perpetuity of all present and future rights to this
+// software under copyright law.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// For more information, please refer to <https://unlicense.org>
+
+package verkle
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func randomKVs(n int) ([][]byte, [][]byte) {
+	keys := make([][]byte, n)
+	values := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		key := make([]byte, 32)
+		val := make([]byte, 32)
+		rand.Read(key)
+		rand.Read(val)
+		keys[i] = key
+		values[i] = val
+	}
+	return keys, values
+}
+
+func TestAddBatchMatchesInsertOrdered(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+
+	keys, values := randomKVs(5000)
+	sortedKeys := make([][]byte, len(keys))
+	sortedValues := make([][]byte, len(values))
+	copy(sortedKeys, keys)
+	copy(sortedValues, values)
+	sort.Sort(&sortableKVs{sortedKeys, sortedValues})
+
+	want := New().(*internalNode)
+	for i, key := range sortedKeys {
+		if err := want.InsertOrdered(key, sortedValues[i], ks, lg1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want.ComputeCommitment(ks, lg1)
+
+	got := New().(*internalNode)
+	if err := got.AddBatch(keys, values, ks, lg1); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(want.Hash(), got.Hash()) {
+		t.Fatalf("AddBatch produced a different root hash than InsertOrdered: %x != %x", got.Hash(), want.Hash())
+	}
+}
+
+// keyWithTopIndex returns a 32-byte key whose top-level child index
+// (see topLevelIndex) is exactly idx, with the remaining bits filled in
+// from fill so distinct keys can still be produced for the same index.
+func keyWithTopIndex(idx int, fill byte) []byte {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = fill
+	}
+	key[0] = byte(idx >> 2)
+	key[1] = (key[1] &^ 0xc0) | byte((idx&3)<<6)
+	return key
+}
+
+// TestAddBatchMergesIntoExistingTree exercises AddBatch against a tree
+// that already has leaves in some of the top-level slots the batch
+// touches, covering both the InsertOrdered fallback path (small bucket)
+// and the parallel subtree-build path (bucket over batchBucketThreshold),
+// to make sure neither silently drops the pre-existing leaf.
+func TestAddBatchMergesIntoExistingTree(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+
+	const (
+		smallBucketIndex = 7
+		largeBucketIndex = 900
+	)
+
+	preExisting := [][]byte{
+		keyWithTopIndex(smallBucketIndex, 0x11),
+		keyWithTopIndex(largeBucketIndex, 0xf0),
+	}
+	preValues := [][]byte{testValue, testValue}
+
+	got := New().(*internalNode)
+	for i, key := range preExisting {
+		if err := got.Insert(key, preValues[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := got.children[smallBucketIndex].(*leafNode); !ok {
+		t.Fatalf("test setup: expected a bare leafNode at slot %d, got %T", smallBucketIndex, got.children[smallBucketIndex])
+	}
+	if _, ok := got.children[largeBucketIndex].(*leafNode); !ok {
+		t.Fatalf("test setup: expected a bare leafNode at slot %d, got %T", largeBucketIndex, got.children[largeBucketIndex])
+	}
+
+	batchKeys := make([][]byte, 0, 153)
+	batchValues := make([][]byte, 0, 153)
+
+	// A couple of keys landing in the same bucket as the small
+	// pre-existing leaf, small enough to stay on the InsertOrdered
+	// fallback path.
+	for i := 0; i < 3; i++ {
+		batchKeys = append(batchKeys, keyWithTopIndex(smallBucketIndex, byte(0x40+i)))
+		batchValues = append(batchValues, testValue)
+	}
+	// Enough keys landing in the same bucket as the large pre-existing
+	// leaf to cross batchBucketThreshold and take the parallel
+	// subtree-build path.
+	for i := 0; i < batchBucketThreshold+20; i++ {
+		batchKeys = append(batchKeys, keyWithTopIndex(largeBucketIndex, byte(i)))
+		batchValues = append(batchValues, testValue)
+	}
+
+	if err := got.AddBatch(batchKeys, batchValues, ks, lg1); err != nil {
+		t.Fatal(err)
+	}
+
+	allKeys := append(append([][]byte{}, preExisting...), batchKeys...)
+	allValues := append(append([][]byte{}, preValues...), batchValues...)
+	sort.Sort(&sortableKVs{allKeys, allValues})
+
+	want := New().(*internalNode)
+	for i, key := range allKeys {
+		if err := want.InsertOrdered(key, allValues[i], ks, lg1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want.ComputeCommitment(ks, lg1)
+
+	if !bytes.Equal(want.Hash(), got.Hash()) {
+		t.Fatalf("AddBatch merged into an existing tree produced a different root hash: %x != %x", got.Hash(), want.Hash())
+	}
+
+	for i, key := range preExisting {
+		val, err := got.Get(key)
+		if err != nil {
+			t.Fatalf("pre-existing key lost after AddBatch: %v", err)
+		}
+		if !bytes.Equal(val, preValues[i]) {
+			t.Fatalf("pre-existing key's value changed after AddBatch: %x != %x", val, preValues[i])
+		}
+	}
+}
+
+func BenchmarkAddBatch100kLeaves(b *testing.B) {
+	benchmarkAddBatchNLeaves(b, 100000)
+}
+
+func BenchmarkAddBatch1MLeaves(b *testing.B) {
+	benchmarkAddBatchNLeaves(b, 1000000)
+}
+
+func benchmarkAddBatchNLeaves(b *testing.B, n int) {
+	rand.Seed(time.Now().UnixNano())
+	keys, values := randomKVs(n)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		root := New().(*internalNode)
+		keysCopy := make([][]byte, n)
+		valuesCopy := make([][]byte, n)
+		copy(keysCopy, keys)
+		copy(valuesCopy, values)
+		if err := root.AddBatch(keysCopy, valuesCopy, ks, lg1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
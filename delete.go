@@ -0,0 +1,114 @@
+package verkle
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/protolambda/go-kzg"
+	"github.com/protolambda/go-kzg/bls"
+)
+
+// ErrDeleteHashedNode is returned when a deletion would have to walk
+// through a hashedNode, i.e. a subtree that hasn't been resolved from
+// storage and so can't be safely modified in place.
+var ErrDeleteHashedNode = errors.New("verkle: cannot delete through a hashed (unresolved) node")
+
+// Delete removes key from the tree rooted at n. It mirrors Insert: no
+// KZG setup is available, so the commitment is left stale and a later
+// ComputeCommitment call is expected to bring it up to date.
+func (n *internalNode) Delete(key []byte) error {
+	return n.delete(key, 0, nil, nil)
+}
+
+// DeleteOrdered is the incremental counterpart to InsertOrdered: besides
+// removing key, it folds the removal into the commitment of every node
+// on the path immediately, using the same delta trick InsertOrdered uses
+// for additions: C_new = C_old + (h(new_child) - h(old_child)) * lg1[slot],
+// rather than recomputing the full degree-InternalNodeNumChildren
+// multi-exponentiation.
+func (n *internalNode) DeleteOrdered(key []byte, ks *kzg.KZGSettings, lg1 []bls.G1Point) error {
+	return n.delete(key, 0, ks, lg1)
+}
+
+func (n *internalNode) delete(key []byte, depth int, ks *kzg.KZGSettings, lg1 []bls.G1Point) error {
+	index := childIndexAtDepth(key, depth)
+	child := n.children[index]
+
+	var oldVal bls.Fr
+	hashChild(&oldVal, child)
+
+	switch c := child.(type) {
+	case nil:
+		return errValueNotPresent
+	case *leafNode:
+		if !bytes.Equal(c.key, key) {
+			return errValueNotPresent
+		}
+		n.children[index] = nil
+	case *internalNode:
+		if err := c.delete(key, depth+1, ks, lg1); err != nil {
+			return err
+		}
+		if replacement, collapse := c.collapseIfSingleton(); collapse {
+			n.children[index] = replacement
+		}
+	case *hashedNode:
+		return ErrDeleteHashedNode
+	default:
+		return errValueNotPresent
+	}
+
+	if ks != nil {
+		var newVal bls.Fr
+		hashChild(&newVal, n.children[index])
+		applyCommitmentDelta(n, index, &oldVal, &newVal, lg1)
+	}
+
+	return nil
+}
+
+// collapseIfSingleton reports whether n now has at most one populated
+// child, and if so what should replace n in its parent: nil if n is now
+// fully empty, or the lone remaining leaf if n has exactly one child and
+// that child is a leafNode. An internal node whose only remaining child
+// is itself an internalNode or a hashedNode is left in place, since
+// pulling up a whole subtree would change the depth its keys are read
+// at.
+func (n *internalNode) collapseIfSingleton() (VerkleNode, bool) {
+	var only VerkleNode
+	count := 0
+	for _, child := range n.children {
+		if child == nil {
+			continue
+		}
+		count++
+		if count > 1 {
+			return nil, false
+		}
+		only = child
+	}
+
+	switch count {
+	case 0:
+		return nil, true
+	case 1:
+		if leaf, ok := only.(*leafNode); ok {
+			return leaf, true
+		}
+	}
+	return nil, false
+}
+
+// applyCommitmentDelta updates n's commitment in place to reflect that
+// the child at index changed from a value hashing to oldVal to one
+// hashing to newVal, without touching any other slot.
+func applyCommitmentDelta(n *internalNode, index int, oldVal, newVal *bls.Fr, lg1 []bls.G1Point) {
+	var diff bls.Fr
+	bls.SubModFr(&diff, newVal, oldVal)
+
+	var term bls.G1Point
+	bls.MulG1(&term, &lg1[index], &diff)
+
+	comm := n.Commitment()
+	bls.AddG1(comm, comm, &term)
+}
@@ -0,0 +1,130 @@
+// This is free and unencumbered software released into the public domain.
+//
+// Anyone is free to copy, modify, publish, use, compile, sell, or
+// distribute this software, either in source code form or as a compiled
+// binary, for any purpose, commercial or non-commercial, and by any
+// means.
+//
+// In jurisdictions that recognize copyright laws, the author or authors
+// of this software dedicate any and all copyright interest in the
+// software to the public domain. We make this dedication for the benefit
+// of the public at large and to the detriment of our heirs and
+// successors. We intend this dedication to be an overt act of
+// relinquishment in perpetuity of all present and future rights to this
+// software under copyright law.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// For more information, please refer to <https://unlicense.org>
+
+package verkle
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestDeleteOnlyLeaf(t *testing.T) {
+	root := New().(*internalNode)
+	if err := root.Insert(zeroKeyTest, testValue); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := root.Delete(zeroKeyTest); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, child := range root.children {
+		if child != nil {
+			t.Fatalf("expected root to be empty after deleting its only leaf, found child at %d", i)
+		}
+	}
+
+	if _, err := root.Get(zeroKeyTest); err != errValueNotPresent {
+		t.Fatalf("expected errValueNotPresent after deletion, got %v", err)
+	}
+}
+
+func TestDeleteOneOfTwoLeavesCollapses(t *testing.T) {
+	root := New().(*internalNode)
+	if err := root.Insert(zeroKeyTest, testValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Insert(oneKeyTest, testValue); err != nil {
+		t.Fatal(err)
+	}
+
+	sharedIndex := childIndexAtDepth(zeroKeyTest, 0)
+	if _, ok := root.children[sharedIndex].(*internalNode); !ok {
+		t.Fatalf("expected zeroKeyTest and oneKeyTest to share a prefix and create a subtree")
+	}
+
+	if err := root.Delete(oneKeyTest); err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, ok := root.children[sharedIndex].(*leafNode)
+	if !ok {
+		t.Fatalf("expected the shared-prefix subtree to collapse into a single leaf, got %T", root.children[sharedIndex])
+	}
+	if !bytes.Equal(leaf.key, zeroKeyTest) {
+		t.Fatalf("collapsed leaf has the wrong key: %x != %x", leaf.key, zeroKeyTest)
+	}
+
+	val, err := root.Get(zeroKeyTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(val, testValue) {
+		t.Fatalf("lost value across collapse: %x != %x", val, testValue)
+	}
+}
+
+func TestFuzzInsertDeleteMatchesFreshTree(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+
+	const n = 500
+	keys, values := randomKVs(n)
+
+	root := New().(*internalNode)
+	for i, key := range keys {
+		if err := root.Insert(key, values[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	remainingKeys := make([][]byte, 0, n)
+	remainingValues := make([][]byte, 0, n)
+	for i, key := range keys {
+		if rand.Intn(2) == 0 {
+			if err := root.Delete(key); err != nil {
+				t.Fatalf("deleting %x: %v", key, err)
+			}
+			continue
+		}
+		remainingKeys = append(remainingKeys, key)
+		remainingValues = append(remainingValues, values[i])
+	}
+
+	fresh := New().(*internalNode)
+	for i, key := range remainingKeys {
+		if err := fresh.Insert(key, remainingValues[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root.ComputeCommitment(ks, lg1)
+	fresh.ComputeCommitment(ks, lg1)
+
+	if !bytes.Equal(root.Hash(), fresh.Hash()) {
+		t.Fatalf("tree with deletions does not match a freshly built tree over the remaining keys: %x != %x", root.Hash(), fresh.Hash())
+	}
+}
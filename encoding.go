@@ -37,7 +37,7 @@ func ParseNode(serialized []byte, tc *TreeConfig) (VerkleNode, error) {
 			if err != nil {
 				return nil, err
 			}
-			return &LeafNode{key: first, value: value}, nil
+			return &leafNode{key: first, value: value}, nil
 		} else if len(first) == 128 {
 			// internal
 			children, _, err := rlp.SplitList(rest)
@@ -53,9 +53,9 @@ func ParseNode(serialized []byte, tc *TreeConfig) (VerkleNode, error) {
 	}
 }
 
-func createInternalNode(bitlist []byte, raw []byte, tc *TreeConfig) (*InternalNode, error) {
+func createInternalNode(bitlist []byte, raw []byte, tc *TreeConfig) (*internalNode, error) {
 	// TODO: fix depth
-	n := (newInternalNode(0, tc)).(*InternalNode)
+	n := (newInternalNode(0, tc)).(*internalNode)
 	indices := indicesFromBitlist(bitlist)
 	for _, index := range indices {
 		el, rest, err := rlp.SplitList(raw)
@@ -87,7 +87,7 @@ func createInternalNode(bitlist []byte, raw []byte, tc *TreeConfig) (*InternalNo
 	return n, nil
 }
 
-func parseLeafNode(raw []byte) (*LeafNode, error) {
+func parseLeafNode(raw []byte) (*leafNode, error) {
 	key, rest, err := rlp.SplitString(raw)
 	if err != nil {
 		return nil, err
@@ -96,15 +96,15 @@ func parseLeafNode(raw []byte) (*LeafNode, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &LeafNode{key, value}, nil
+	return &leafNode{key, value}, nil
 }
 
-func parseHashedNode(raw []byte) (*HashedNode, error) {
+func parseHashedNode(raw []byte) (*hashedNode, error) {
 	h, _, err := rlp.SplitString(raw)
 	if err != nil {
 		return nil, err
 	}
-	return &HashedNode{hash: common.BytesToHash(h)}, nil
+	return &hashedNode{hash: common.BytesToHash(h)}, nil
 }
 
 func indicesFromBitlist(bitlist []byte) []int {
@@ -0,0 +1,192 @@
+package verkle
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/protolambda/go-kzg/bls"
+)
+
+// SSZ node-type tags. These are only meaningful within the SSZ encoding;
+// the RLP encoding in encoding.go tells nodes apart by their element
+// count instead.
+const (
+	sszTypeInternal byte = 1
+	sszTypeLeaf     byte = 2
+	sszTypeHashed   byte = 3
+)
+
+const (
+	sszBitlistLen = InternalNodeNumChildren / 8
+	sszHashLen    = 32
+	sszCommLen    = 48
+	sszKeyLen     = 32
+	sszValueLen   = 32
+)
+
+// NodeCodec selects how VerkleNode values are turned into bytes and
+// back. RLPCodec keeps the package's original ad-hoc RLP format so
+// existing callers are unaffected by this change; SSZCodec switches to
+// the SSZ container the Ethereum verkle trie proposal standardizes on.
+// Consumers pick a codec per tree rather than the package picking one
+// globally.
+type NodeCodec interface {
+	Parse(serialized []byte, tc *TreeConfig) (VerkleNode, error)
+	Serialize(n VerkleNode) ([]byte, error)
+}
+
+type rlpCodec struct{}
+
+func (rlpCodec) Parse(serialized []byte, tc *TreeConfig) (VerkleNode, error) {
+	return ParseNode(serialized, tc)
+}
+
+func (rlpCodec) Serialize(n VerkleNode) ([]byte, error) {
+	return n.Serialize()
+}
+
+type sszCodec struct{}
+
+func (sszCodec) Parse(serialized []byte, tc *TreeConfig) (VerkleNode, error) {
+	return ParseNodeSSZ(serialized, tc)
+}
+
+func (sszCodec) Serialize(n VerkleNode) ([]byte, error) {
+	switch n := n.(type) {
+	case *internalNode:
+		return n.SerializeSSZ()
+	case *leafNode:
+		return n.SerializeSSZ()
+	case *hashedNode:
+		return n.SerializeSSZ()
+	default:
+		return nil, errors.New(ErrInvalidNodeEncoding)
+	}
+}
+
+// RLPCodec and SSZCodec are the two NodeCodec implementations the
+// package ships out of the box.
+var (
+	RLPCodec NodeCodec = rlpCodec{}
+	SSZCodec NodeCodec = sszCodec{}
+)
+
+// ParseNodeSSZ is the SSZ counterpart of ParseNode: it decodes the
+// {node_type, ...} container produced by SerializeSSZ instead of the
+// package's original RLP encoding.
+func ParseNodeSSZ(serialized []byte, tc *TreeConfig) (VerkleNode, error) {
+	if len(serialized) < 1 {
+		return nil, errors.New(ErrInvalidNodeEncoding)
+	}
+
+	switch serialized[0] {
+	case sszTypeInternal:
+		return parseInternalNodeSSZ(serialized[1:], tc)
+	case sszTypeLeaf:
+		return parseLeafNodeSSZ(serialized[1:])
+	case sszTypeHashed:
+		return parseHashedNodeSSZ(serialized[1:])
+	default:
+		return nil, errors.New(ErrInvalidNodeEncoding)
+	}
+}
+
+// SerializeSSZ encodes n as the SSZ container
+// {node_type: uint8, bitlist: Bitvector[1024], children: List[Bytes32, 1024], commitment: Bytes48}.
+// Children that are empty have their bit left clear in the bitlist and
+// are omitted from the children list, the same way the RLP encoding
+// already omits them.
+func (n *internalNode) SerializeSSZ() ([]byte, error) {
+	bitlist := make([]byte, sszBitlistLen)
+	var children []byte
+
+	for i, child := range n.children {
+		if child == nil {
+			continue
+		}
+		bitlist[i/8] |= 1 << uint(i%8)
+
+		h := child.Hash()
+		if len(h) != sszHashLen {
+			return nil, errors.New(ErrInvalidNodeEncoding)
+		}
+		children = append(children, h...)
+	}
+
+	comm := bls.ToCompressedG1(n.Commitment())
+	if len(comm) != sszCommLen {
+		return nil, errors.New(ErrInvalidNodeEncoding)
+	}
+
+	out := make([]byte, 0, 1+len(bitlist)+len(children)+len(comm))
+	out = append(out, sszTypeInternal)
+	out = append(out, bitlist...)
+	out = append(out, children...)
+	out = append(out, comm...)
+	return out, nil
+}
+
+func parseInternalNodeSSZ(raw []byte, tc *TreeConfig) (*internalNode, error) {
+	if len(raw) < sszBitlistLen+sszCommLen {
+		return nil, errors.New(ErrInvalidNodeEncoding)
+	}
+
+	bitlist := raw[:sszBitlistLen]
+	raw = raw[sszBitlistLen:]
+
+	indices := indicesFromBitlist(bitlist)
+	if len(raw) != len(indices)*sszHashLen+sszCommLen {
+		return nil, errors.New(ErrInvalidNodeEncoding)
+	}
+
+	n := (newInternalNode(0, tc)).(*internalNode)
+	for _, index := range indices {
+		n.children[index] = &hashedNode{hash: common.BytesToHash(raw[:sszHashLen])}
+		raw = raw[sszHashLen:]
+	}
+
+	// The remaining sszCommLen bytes are the node's own commitment; the
+	// RLP path doesn't round-trip this value either, so it is validated
+	// for length only and not stored back onto n.
+	return n, nil
+}
+
+// SerializeSSZ encodes n as {node_type: uint8, key: Bytes32, value: Bytes32}.
+// This tree keeps a single value per leaf rather than the 256-suffix
+// stem extension the full verkle trie spec uses, so the container is
+// narrowed accordingly while keeping the same node_type-tagged shape.
+func (n *leafNode) SerializeSSZ() ([]byte, error) {
+	if len(n.key) != sszKeyLen || len(n.value) != sszValueLen {
+		return nil, errors.New(ErrInvalidNodeEncoding)
+	}
+
+	out := make([]byte, 0, 1+sszKeyLen+sszValueLen)
+	out = append(out, sszTypeLeaf)
+	out = append(out, n.key...)
+	out = append(out, n.value...)
+	return out, nil
+}
+
+func parseLeafNodeSSZ(raw []byte) (*leafNode, error) {
+	if len(raw) != sszKeyLen+sszValueLen {
+		return nil, errors.New(ErrInvalidNodeEncoding)
+	}
+	key := raw[:sszKeyLen]
+	value := raw[sszKeyLen:]
+	return &leafNode{key: key, value: value}, nil
+}
+
+// SerializeSSZ encodes n as {node_type: uint8, hash: Bytes32}.
+func (n *hashedNode) SerializeSSZ() ([]byte, error) {
+	out := make([]byte, 0, 1+sszHashLen)
+	out = append(out, sszTypeHashed)
+	out = append(out, n.hash.Bytes()...)
+	return out, nil
+}
+
+func parseHashedNodeSSZ(raw []byte) (*hashedNode, error) {
+	if len(raw) != sszHashLen {
+		return nil, errors.New(ErrInvalidNodeEncoding)
+	}
+	return &hashedNode{hash: common.BytesToHash(raw)}, nil
+}
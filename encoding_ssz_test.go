@@ -0,0 +1,282 @@
+package verkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// buildFullNodeTree returns a tree with one leaf directly under the root
+// for each of the InternalNodeNumChildren top-level slots: a single
+// level, never a nested internalNode child. It's a narrow sanity check,
+// not a stand-in for a realistic tree; TestSSZRoundTripAtScale covers
+// the multi-level case a ~10k-leaf tree actually produces. The RLP
+// encoding inlines each child's key/value directly in the parent's
+// bytes, so a single ParseNode call recovers every value; the SSZ
+// encoding only ever references a child by its Bytes32 hash (per the
+// `children: List[Bytes32, 1024]` container), so recovering a leaf's
+// value after an SSZ round trip requires resolving that hash against a
+// store of each node's own SerializeSSZ bytes, the same way a real
+// store-backed tree would.
+func buildFullNodeTree(t *testing.T) (VerkleNode, [][]byte, [][]byte) {
+	t.Helper()
+
+	n := InternalNodeNumChildren
+	keys := make([][]byte, n)
+	values := make([][]byte, n)
+
+	root := New()
+	for i := 0; i < n; i++ {
+		key := make([]byte, 32)
+		val := make([]byte, 32)
+		binary.BigEndian.PutUint16(key[:2], uint16(i)<<6)
+		binary.BigEndian.PutUint32(val, uint32(i))
+		if err := root.Insert(key, val); err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+		values[i] = val
+	}
+	root.ComputeCommitment(ks, lg1)
+
+	return root, keys, values
+}
+
+func TestSSZRoundTripMatchesRLP(t *testing.T) {
+	root, keys, values := buildFullNodeTree(t)
+	internal := root.(*internalNode)
+
+	// A hash -> SSZ-bytes store, so that the hashedNode placeholders an
+	// SSZ round trip produces for each child can be resolved back to
+	// their real leaf bytes.
+	sszStore := make(map[common.Hash][]byte, len(internal.children))
+	for _, child := range internal.children {
+		leaf, ok := child.(*leafNode)
+		if !ok {
+			continue
+		}
+		encoded, err := leaf.SerializeSSZ()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sszStore[common.BytesToHash(leaf.Hash())] = encoded
+	}
+
+	rlpEncoded, err := internal.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sszEncoded, err := internal.SerializeSSZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rlpParsed, err := ParseNode(rlpEncoded, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sszParsed, err := ParseNodeSSZ(sszEncoded, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(rlpParsed.Hash(), root.Hash()) {
+		t.Fatalf("RLP round trip changed the root hash: %x != %x", rlpParsed.Hash(), root.Hash())
+	}
+	if !bytes.Equal(sszParsed.Hash(), root.Hash()) {
+		t.Fatalf("SSZ round trip changed the root hash: %x != %x", sszParsed.Hash(), root.Hash())
+	}
+
+	sszInternal := sszParsed.(*internalNode)
+
+	for i, key := range keys {
+		rlpVal, err := rlpParsed.Get(key)
+		if err != nil {
+			t.Fatalf("RLP round trip: %v", err)
+		}
+
+		index := childIndexAtDepth(key, 0)
+		hashed, ok := sszInternal.children[index].(*hashedNode)
+		if !ok {
+			t.Fatalf("expected SSZ round trip to leave a hashedNode placeholder at slot %d, got %T", index, sszInternal.children[index])
+		}
+		encoded, ok := sszStore[hashed.hash]
+		if !ok {
+			t.Fatalf("no stored SSZ bytes for hash %x", hashed.hash)
+		}
+		resolved, err := ParseNodeSSZ(encoded, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sszVal, err := resolved.Get(key)
+		if err != nil {
+			t.Fatalf("SSZ round trip: %v", err)
+		}
+
+		if !bytes.Equal(rlpVal, values[i]) || !bytes.Equal(sszVal, values[i]) {
+			t.Fatalf("round trip lost value for key %x: rlp=%x ssz=%x want=%x", key, rlpVal, sszVal, values[i])
+		}
+	}
+}
+
+// sszStoreForTree walks root and records every node's own SerializeSSZ
+// bytes under its hash, the way a store-backed tree would persist each
+// node as it's written, so a later SSZ round trip can resolve hashedNode
+// placeholders at any depth, not just the one directly under the root.
+func sszStoreForTree(t *testing.T, root VerkleNode, store map[common.Hash][]byte) {
+	t.Helper()
+
+	switch n := root.(type) {
+	case *internalNode:
+		encoded, err := n.SerializeSSZ()
+		if err != nil {
+			t.Fatal(err)
+		}
+		store[common.BytesToHash(n.Hash())] = encoded
+		for _, child := range n.children {
+			if child == nil {
+				continue
+			}
+			sszStoreForTree(t, child, store)
+		}
+	case *leafNode:
+		encoded, err := n.SerializeSSZ()
+		if err != nil {
+			t.Fatal(err)
+		}
+		store[common.BytesToHash(n.Hash())] = encoded
+	}
+}
+
+// resolveSSZTree fully materializes the SSZ encoding of a tree back into
+// VerkleNodes, recursively resolving every hashedNode placeholder
+// against store, at any depth, the way a store-backed tree would resolve
+// a path on demand while walking it.
+func resolveSSZTree(encoded []byte, store map[common.Hash][]byte) (VerkleNode, error) {
+	parsed, err := ParseNodeSSZ(encoded, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	internal, ok := parsed.(*internalNode)
+	if !ok {
+		return parsed, nil
+	}
+
+	for i, child := range internal.children {
+		hashed, ok := child.(*hashedNode)
+		if !ok {
+			continue
+		}
+		childEncoded, ok := store[hashed.hash]
+		if !ok {
+			return nil, errNotFoundInStore(hashed.hash)
+		}
+		resolved, err := resolveSSZTree(childEncoded, store)
+		if err != nil {
+			return nil, err
+		}
+		internal.children[i] = resolved
+	}
+
+	return internal, nil
+}
+
+// errNotFoundInStore reports a hash a test's in-memory SSZ store has no
+// bytes for, so a missing entry fails with the hash that was missing
+// instead of a generic "not ok".
+func errNotFoundInStore(h common.Hash) error {
+	return &hashNotFoundError{h}
+}
+
+type hashNotFoundError struct{ hash common.Hash }
+
+func (e *hashNotFoundError) Error() string {
+	return "no stored SSZ bytes for hash " + e.hash.Hex()
+}
+
+// TestSSZRoundTripAtScale builds a ~10k-leaf tree, which at
+// InternalNodeNumChildren fan-out necessarily spans multiple levels, and
+// confirms an SSZ round trip through a hash-addressed store recovers the
+// same root hash and every key's value, exercising the multi-level
+// resolution buildFullNodeTree's single level can't.
+func TestSSZRoundTripAtScale(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+
+	const n = 10000
+	keys, values := randomKVs(n)
+
+	root := New()
+	for i, key := range keys {
+		if err := root.Insert(key, values[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root.ComputeCommitment(ks, lg1)
+
+	internal := root.(*internalNode)
+	var nested bool
+	for _, child := range internal.children {
+		if _, ok := child.(*internalNode); ok {
+			nested = true
+			break
+		}
+	}
+	if !nested {
+		t.Fatal("test setup: expected at least one nested internalNode child at this scale")
+	}
+
+	store := make(map[common.Hash][]byte)
+	sszStoreForTree(t, root, store)
+
+	sszEncoded, err := internal.SerializeSSZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := resolveSSZTree(sszEncoded, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(resolved.Hash(), root.Hash()) {
+		t.Fatalf("SSZ round trip changed the root hash: %x != %x", resolved.Hash(), root.Hash())
+	}
+
+	for i, key := range keys {
+		val, err := resolved.Get(key)
+		if err != nil {
+			t.Fatalf("SSZ round trip: %v", err)
+		}
+		if !bytes.Equal(val, values[i]) {
+			t.Fatalf("round trip lost value for key %x: got=%x want=%x", key, val, values[i])
+		}
+	}
+}
+
+func TestNodeCodecSelection(t *testing.T) {
+	root, _, _ := buildFullNodeTree(t)
+
+	rlpBytes, err := RLPCodec.Serialize(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sszBytes, err := SSZCodec.Serialize(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(rlpBytes, sszBytes) {
+		t.Fatal("RLPCodec and SSZCodec produced identical bytes, expected different wire formats")
+	}
+
+	if _, err := RLPCodec.Parse(rlpBytes, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SSZCodec.Parse(sszBytes, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,275 @@
+package verkle
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/protolambda/go-kzg"
+	"github.com/protolambda/go-kzg/bls"
+)
+
+// ErrVerifyMultiProof is returned by VerifyMultiProof when any step of
+// any key's path fails to verify, i.e. the proof does not attest to the
+// claimed values at the claimed keys.
+var ErrVerifyMultiProof = errors.New("verkle: multiproof verification failed")
+
+// MultiProof opens an arbitrary number of keys in one tree. Rather than
+// batching every path into one aggregated polynomial and a single
+// pairing check — which would need multi-point commit/open/verify
+// methods go-kzg's KZGSettings doesn't expose — it proves each step of
+// each key's path with its own single-point KZG proof: the quotient
+// (f(X) - y) / (X - z) at the slot's root of unity z is committed over
+// ks.SecretG1 and checked with KZGSettings.CheckProofSingle. A proof
+// therefore carries one opening, and needs one pairing check, per step
+// rather than one for the whole proof.
+//
+// Unlike a proof checked against a live tree, MultiProof carries every
+// intermediate commitment it relied on (Commitments) itself, so
+// VerifyMultiProof only ever needs the root commitment the verifier
+// already trusts plus the keys being opened — never the materialized
+// tree the prover built the proof from.
+type MultiProof struct {
+	// Commitments holds, for every step of every key's path, the
+	// commitment of the node visited at that step, flattened across all
+	// keys in the same order as Depths describes.
+	Commitments []*bls.G1Point
+	// Zs holds, for every commitment in Commitments, the evaluation
+	// point (a root of unity in ks's domain) of the child slot that was
+	// followed at that step.
+	Zs []bls.Fr
+	// Ys holds, for every (commitment, point) pair, the claimed
+	// evaluation y_i: the hash-to-Fr value of the child slot that was
+	// followed at that step.
+	Ys []bls.Fr
+	// Proofs holds the KZG single-point opening proof for each
+	// (commitment, point, value) triple in Commitments/Zs/Ys.
+	Proofs []*bls.G1Point
+	// Depths holds, for each key in the order keys were passed to
+	// MakeMultiProof, how many steps of Commitments/Zs/Ys/Proofs belong
+	// to it, so the flattened lists can be sliced back up per key.
+	Depths []int
+}
+
+// proofElement is one step of a key's path down the tree: the
+// commitment of the node visited, the domain index and Fr-encoded
+// evaluation point corresponding to the child slot that was followed,
+// that child's hash-to-Fr value, and the node's own polynomial in
+// coefficient form, from which MakeMultiProof builds the opening proof
+// at that point.
+type proofElement struct {
+	comm   *bls.G1Point
+	index  int
+	z      bls.Fr
+	y      bls.Fr
+	coeffs []bls.Fr
+}
+
+// MakeMultiProof builds a proof that opens every key in keys against
+// root: for each key, it walks root collecting one proofElement per
+// internalNode on the path, then computes a KZG single-point opening
+// proof for each element at its point z. The prover needs
+// root itself; the resulting proof does not.
+func MakeMultiProof(root VerkleNode, keys [][]byte, ks *kzg.KZGSettings) (*MultiProof, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("verkle: MakeMultiProof needs at least one key")
+	}
+
+	var elements []proofElement
+	depths := make([]int, len(keys))
+	for i, key := range keys {
+		path, err := collectProofPath(root, key, ks)
+		if err != nil {
+			return nil, err
+		}
+		depths[i] = len(path)
+		elements = append(elements, path...)
+	}
+
+	commitments := make([]*bls.G1Point, len(elements))
+	zs := make([]bls.Fr, len(elements))
+	ys := make([]bls.Fr, len(elements))
+	proofs := make([]*bls.G1Point, len(elements))
+
+	for i, el := range elements {
+		commitments[i] = el.comm
+		zs[i] = el.z
+		ys[i] = el.y
+
+		// ComputeProofSingle only opens at an integer point, so the
+		// quotient (f(X) - y) / (X - z) at the root of unity z is built
+		// here by synthetic division and committed directly.
+		quot := make([]bls.Fr, len(el.coeffs)-1)
+		var carry bls.Fr
+		bls.CopyFr(&carry, &bls.ZERO)
+		for k := len(el.coeffs) - 1; k > 0; k-- {
+			var tmp bls.Fr
+			bls.MulModFr(&tmp, &carry, &el.z)
+			bls.AddModFr(&carry, &tmp, &el.coeffs[k])
+			quot[k-1] = carry
+		}
+		proofs[i] = bls.LinCombG1(ks.SecretG1[:len(quot)], quot)
+	}
+
+	return &MultiProof{Commitments: commitments, Zs: zs, Ys: ys, Proofs: proofs, Depths: depths}, nil
+}
+
+// VerifyMultiProof checks proof against rootComm and keys. It needs only
+// the root commitment the verifier already trusts, never the
+// materialized tree the proof was built from: every intermediate
+// commitment it needs comes from proof.Commitments, each one checked
+// against its parent (the root, for the first step of each key; the
+// previous step's claimed y_i otherwise) and against the key's own
+// routing before its single-point opening proof is checked with
+// ks.CheckProofSingle.
+func VerifyMultiProof(proof *MultiProof, rootComm *bls.G1Point, keys [][]byte, ks *kzg.KZGSettings) (bool, error) {
+	if len(proof.Depths) != len(keys) {
+		return false, errors.New("verkle: multiproof depth count does not match key count")
+	}
+	if len(proof.Commitments) != len(proof.Zs) || len(proof.Commitments) != len(proof.Ys) || len(proof.Commitments) != len(proof.Proofs) {
+		return false, errors.New("verkle: multiproof element count mismatch")
+	}
+
+	offset := 0
+	for ki, key := range keys {
+		depth := proof.Depths[ki]
+		if offset+depth > len(proof.Commitments) {
+			return false, errors.New("verkle: multiproof element count mismatch")
+		}
+
+		for d := 0; d < depth; d++ {
+			comm := proof.Commitments[offset+d]
+			z := proof.Zs[offset+d]
+			y := proof.Ys[offset+d]
+
+			if d == 0 {
+				if !bls.EqualG1(comm, rootComm) {
+					return false, ErrVerifyMultiProof
+				}
+			} else {
+				var wantY bls.Fr
+				hashToFr(&wantY, common.BytesToHash(bls.ToCompressedG1(comm)))
+				if !bls.EqualFr(&wantY, &proof.Ys[offset+d-1]) {
+					return false, ErrVerifyMultiProof
+				}
+			}
+
+			wantZ := domainPoint(ks, childIndexAtDepth(key, d))
+			if !bls.EqualFr(&wantZ, &z) {
+				return false, ErrVerifyMultiProof
+			}
+
+			if !ks.CheckProofSingle(comm, proof.Proofs[offset+d], &z, &y) {
+				return false, ErrVerifyMultiProof
+			}
+		}
+
+		offset += depth
+	}
+	if offset != len(proof.Commitments) {
+		return false, errors.New("verkle: multiproof carries unused elements")
+	}
+
+	return true, nil
+}
+
+// collectProofPath walks root following key, collecting, at each
+// internalNode visited, its commitment, the domain index and evaluation
+// point of the child slot that key routes through, that child's
+// hash-to-Fr value, and the node's own polynomial in coefficient form
+// (the inverse FFT of its per-child evaluations) that MakeMultiProof
+// needs to build an opening proof. Only the prover calls this: it
+// requires the materialized tree, which is exactly what VerifyMultiProof
+// avoids needing.
+func collectProofPath(root VerkleNode, key []byte, ks *kzg.KZGSettings) ([]proofElement, error) {
+	var path []proofElement
+
+	node := root
+	depth := 0
+	for {
+		internal, ok := node.(*internalNode)
+		if !ok {
+			break
+		}
+
+		index := childIndexAtDepth(key, depth)
+		z := domainPoint(ks, index)
+
+		var y bls.Fr
+		hashChild(&y, internal.children[index])
+
+		coeffs, err := ks.FFTSettings.FFT(internal.toLagrangeEvals(), true)
+		if err != nil {
+			return nil, err
+		}
+
+		path = append(path, proofElement{
+			comm:   internal.Commitment(),
+			index:  index,
+			z:      z,
+			y:      y,
+			coeffs: coeffs,
+		})
+
+		node = internal.children[index]
+		depth++
+	}
+
+	return path, nil
+}
+
+// childIndexAtDepth returns the child slot that key routes through at
+// the given depth, i.e. the same 10-bit window internalNode.Insert uses
+// to pick a child. depth must not exceed maxKeyDepth(len(key)): beyond
+// that, the window would need a byte past the end of key, so the caller
+// is responsible for bounding depth (see commonDepth).
+func childIndexAtDepth(key []byte, depth int) int {
+	bitOffset := depth * 10
+	byteOffset := bitOffset / 8
+	bitShift := uint(bitOffset % 8)
+
+	// The very last window a key's bits can fill only has the high byte
+	// available; the low byte is past the end of key and reads as zero,
+	// the same way a partial final word would in a bitstream.
+	var lo uint16
+	if byteOffset+1 < len(key) {
+		lo = uint16(key[byteOffset+1])
+	}
+	window := uint16(key[byteOffset])<<8 | lo
+	return int((window >> (6 - bitShift)) & 0x3ff)
+}
+
+// maxKeyDepth returns the deepest depth at which childIndexAtDepth can
+// still read a key of the given length without running off its end.
+func maxKeyDepth(keyLen int) int {
+	return ((keyLen-1)*8 + 7) / 10
+}
+
+// domainPoint returns the index-th root of unity in ks's evaluation
+// domain: the actual field element a child slot opens at, as opposed to
+// its bare integer index.
+func domainPoint(ks *kzg.KZGSettings, index int) bls.Fr {
+	return ks.FFTSettings.ExpandedRootsOfUnity[index]
+}
+
+// hashChild returns the value a child contributes to its parent's
+// polynomial: zero for an empty slot, otherwise the hash-to-Fr of the
+// child's Hash(), whether it is a hashed, leaf or internal node.
+func hashChild(out *bls.Fr, child VerkleNode) {
+	if child == nil {
+		bls.CopyFr(out, &bls.ZERO)
+		return
+	}
+	hashToFr(out, common.BytesToHash(child.Hash()))
+}
+
+// toLagrangeEvals returns the evaluation, at every one of the
+// InternalNodeNumChildren roots of unity, of the polynomial that n's
+// commitment is a KZG commitment to: the hash-to-Fr value of each child
+// slot, zero where a slot is empty.
+func (n *internalNode) toLagrangeEvals() []bls.Fr {
+	evals := make([]bls.Fr, len(n.children))
+	for i, child := range n.children {
+		hashChild(&evals[i], child)
+	}
+	return evals
+}
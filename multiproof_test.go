@@ -0,0 +1,55 @@
+package verkle
+
+import "testing"
+
+func TestMultiProofRoundTrip(t *testing.T) {
+	root := New()
+	root.Insert(zeroKeyTest, testValue)
+	root.Insert(fourtyKeyTest, testValue)
+	root.Insert(ffx32KeyTest, testValue)
+	root.ComputeCommitment(ks, lg1)
+
+	keys := [][]byte{zeroKeyTest, ffx32KeyTest}
+
+	proof, err := MakeMultiProof(root, keys, ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The verifier only ever sees the root commitment, never the
+	// materialized tree the proof was built from.
+	rootComm := root.(*internalNode).Commitment()
+
+	ok, err := VerifyMultiProof(proof, rootComm, keys, ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("multiproof failed to verify against its own root commitment")
+	}
+}
+
+func TestMultiProofRejectsWrongRoot(t *testing.T) {
+	root := New()
+	root.Insert(zeroKeyTest, testValue)
+	root.Insert(fourtyKeyTest, testValue)
+	root.Insert(ffx32KeyTest, testValue)
+	root.ComputeCommitment(ks, lg1)
+
+	keys := [][]byte{zeroKeyTest, ffx32KeyTest}
+
+	proof, err := MakeMultiProof(root, keys, ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := New()
+	other.Insert(oneKeyTest, testValue)
+	other.ComputeCommitment(ks, lg1)
+	otherComm := other.(*internalNode).Commitment()
+
+	ok, err := VerifyMultiProof(proof, otherComm, keys, ks)
+	if err == nil && ok {
+		t.Fatal("multiproof verified against a root commitment it was not built from")
+	}
+}
@@ -0,0 +1,195 @@
+package verkle
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/protolambda/go-kzg"
+	"github.com/protolambda/go-kzg/bls"
+)
+
+// stackEntry is one open internal node on a StackTree's stack: the
+// polynomial being accumulated for it, and slot, its fixed position
+// within its parent's polynomial. slot is set once, when the entry is
+// pushed, and never changes afterwards — it must not be confused with
+// whatever child slot the key currently being processed happens to
+// occupy inside this entry itself.
+type stackEntry struct {
+	depth   int
+	poly    []bls.Fr
+	slot    int
+	touched bool
+}
+
+// StackTree builds a verkle commitment from a stream of (key, value)
+// pairs that arrive in strictly increasing key order without ever
+// holding the whole tree in memory at once. It keeps only the internal
+// nodes still on the path of the most recently inserted key; as soon as
+// a new key proves a subtree can never be visited again, that subtree is
+// committed and discarded, mirroring go-ethereum's StackTrie. Memory
+// usage is O(depth * InternalNodeNumChildren) rather than O(n).
+//
+// A key's own leaf contribution is never written into a polynomial the
+// moment it arrives: the depth it ultimately belongs at depends on how
+// much prefix it shares with the key that follows it, which isn't known
+// until that next key (or a final Commitment/Hash call) arrives. So
+// StackTree holds the most recent key back as "pending" and only folds
+// it in once its final depth is settled.
+type StackTree struct {
+	ks  *kzg.KZGSettings
+	lg1 []bls.G1Point
+
+	stack []*stackEntry
+
+	pendingKey   []byte
+	pendingValue []byte
+}
+
+// NewStackTree creates an empty StackTree. ks and lg1 are the same KZG
+// setup that would otherwise be passed to ComputeCommitment.
+func NewStackTree(ks *kzg.KZGSettings, lg1 []bls.G1Point) *StackTree {
+	return &StackTree{ks: ks, lg1: lg1}
+}
+
+// Update inserts the next (key, value) pair. Keys must be strictly
+// increasing: a stack tree's whole point is that a closed subtree is
+// gone for good, so an out-of-order key would need a subtree that no
+// longer exists.
+func (s *StackTree) Update(key, value []byte) error {
+	if s.pendingKey != nil && bytes.Compare(key, s.pendingKey) <= 0 {
+		return errors.New("verkle: StackTree.Update requires strictly increasing keys")
+	}
+
+	if s.pendingKey != nil {
+		// The pending key's final depth isn't just where it diverges
+		// from key: if the stack is already deeper than that — because
+		// the key before it shared an even longer prefix with it — the
+		// pending key belongs at that deeper level too, alongside that
+		// earlier key, not at the shallower depth key's arrival alone
+		// would suggest. Settle (and write) there first, then shrink
+		// down to where key actually diverges from it.
+		d := commonDepth(s.pendingKey, key)
+		branch := d
+		if top := len(s.stack) - 1; top > branch {
+			branch = top
+		}
+		s.settleAt(branch)
+		s.writePending()
+		s.settleAt(d)
+	}
+
+	s.pendingKey = key
+	s.pendingValue = value
+	return nil
+}
+
+// settleAt grows or shrinks the stack so that it holds exactly the
+// entries for depths 0..branch: entries deeper than branch are closed
+// with closeTop, which commits each one that was written into and folds
+// it into its parent (untouched ones are just dropped), while entries up
+// to branch are pushed if they don't exist yet, ready to receive the
+// pending key's contribution.
+func (s *StackTree) settleAt(branch int) {
+	for len(s.stack) > branch+1 {
+		s.closeTop()
+	}
+
+	for len(s.stack) <= branch {
+		depth := len(s.stack)
+		entry := &stackEntry{depth: depth, poly: make([]bls.Fr, InternalNodeNumChildren)}
+		if depth > 0 {
+			entry.slot = childIndexAtDepth(s.pendingKey, depth-1)
+		}
+		s.stack = append(s.stack, entry)
+	}
+}
+
+// writePending folds the pending key's leaf value into the polynomial
+// of the entry now on top of the stack, which settleAt has just placed
+// at the depth the pending key's leaf belongs at: the deeper of where it
+// diverges from its predecessor and from its successor, if any.
+func (s *StackTree) writePending() {
+	leaf := &leafNode{key: s.pendingKey, value: s.pendingValue}
+	var y bls.Fr
+	hashChild(&y, leaf)
+
+	top := s.stack[len(s.stack)-1]
+	top.poly[childIndexAtDepth(s.pendingKey, top.depth)] = y
+	top.touched = true
+}
+
+// closeTop commits the node at the top of the stack, folds its
+// hash-to-Fr value into its parent's polynomial, and pops it, freeing
+// the closed node's polynomial.
+func (s *StackTree) closeTop() {
+	top := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+
+	if !top.touched {
+		return
+	}
+
+	comm := bls.LinCombG1(s.lg1, top.poly)
+
+	if len(s.stack) == 0 {
+		// top was the root; nothing to fold into, so park it back on
+		// the stack so Commitment/Hash can still find its polynomial.
+		parked := &stackEntry{depth: 0, poly: top.poly, touched: true}
+		s.stack = append(s.stack, parked)
+		_ = comm
+		return
+	}
+
+	var y bls.Fr
+	hashToFr(&y, common.BytesToHash(bls.ToCompressedG1(comm)))
+
+	parent := s.stack[len(s.stack)-1]
+	parent.poly[top.slot] = y
+	parent.touched = true
+}
+
+// Commitment flushes the pending key, if any, then closes every
+// remaining open node and returns the resulting root commitment.
+func (s *StackTree) Commitment() *bls.G1Point {
+	if s.pendingKey != nil {
+		// There's no successor to compare against anymore, so the
+		// pending key settles at whatever depth it already reached
+		// relative to its own predecessor.
+		branch := len(s.stack) - 1
+		if branch < 0 {
+			branch = 0
+		}
+		s.settleAt(branch)
+		s.writePending()
+		s.pendingKey = nil
+	}
+
+	if len(s.stack) == 0 {
+		return bls.LinCombG1(s.lg1, make([]bls.Fr, InternalNodeNumChildren))
+	}
+	for len(s.stack) > 1 {
+		s.closeTop()
+	}
+	return bls.LinCombG1(s.lg1, s.stack[0].poly)
+}
+
+// Hash returns the compressed form of Commitment, the same way
+// internalNode.Hash does.
+func (s *StackTree) Hash() []byte {
+	return bls.ToCompressedG1(s.Commitment())
+}
+
+// commonDepth returns the shallowest depth, in internalNode child-slot
+// units, at which keys a and b route to a different child. If a and b
+// still agree at the deepest depth childIndexAtDepth can read, it
+// returns that depth: the keys collide all the way down, and whichever
+// node sits there is the deepest one that can ever represent both.
+func commonDepth(a, b []byte) int {
+	max := maxKeyDepth(len(a))
+	depth := 0
+	for depth < max && childIndexAtDepth(a, depth) == childIndexAtDepth(b, depth) {
+		depth++
+	}
+	return depth
+}
@@ -0,0 +1,113 @@
+// This is free and unencumbered software released into the public domain.
+//
+// Anyone is free to copy, modify, publish, use, compile, sell, or
+// distribute this software, either in source code form or as a compiled
+// binary, for any purpose, commercial or non-commercial, and by any
+// means.
+//
+// In jurisdictions that recognize copyright laws, the author or authors
+// of this software dedicate any and all copyright interest in the
+// software to the public domain. We make this dedication for the benefit
+// of the public at large and to the detriment of our heirs and
+// successors. We intend this dedication to be an overt act of
+// relinquishment in perpetuity of all present and future rights to this
+// software under copyright law.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+// OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+// ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+//
+// For more information, please refer to <https://unlicense.org>
+
+package verkle
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/protolambda/go-kzg/bls"
+)
+
+func TestStackTreeMatchesComputeCommitment(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+
+	keys, values := randomKVs(2000)
+	sort.Sort(&sortableKVs{keys, values})
+
+	root := New()
+	for i, key := range keys {
+		if err := root.InsertOrdered(key, values[i], ks, lg1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := root.ComputeCommitment(ks, lg1)
+
+	st := NewStackTree(ks, lg1)
+	for i, key := range keys {
+		if err := st.Update(key, values[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := st.Commitment()
+
+	if !bytes.Equal(bls.ToCompressedG1(want), bls.ToCompressedG1(got)) {
+		t.Fatalf("StackTree commitment does not match ComputeCommitment: %x != %x", bls.ToCompressedG1(got), bls.ToCompressedG1(want))
+	}
+}
+
+func TestStackTreeMatchesComputeCommitmentSharedPrefix(t *testing.T) {
+	// zeroKeyTest and oneKeyTest agree on every child-slot window but
+	// the deepest one, so the second key pushes a chain of open nodes
+	// all the way down and ffx32KeyTest then closes that whole chain
+	// back into the root.
+	keys := [][]byte{zeroKeyTest, oneKeyTest, ffx32KeyTest}
+	values := [][]byte{testValue, testValue, testValue}
+
+	root := New()
+	for i, key := range keys {
+		if err := root.InsertOrdered(key, values[i], ks, lg1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := root.ComputeCommitment(ks, lg1)
+
+	st := NewStackTree(ks, lg1)
+	for i, key := range keys {
+		if err := st.Update(key, values[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := st.Commitment()
+
+	if !bytes.Equal(bls.ToCompressedG1(want), bls.ToCompressedG1(got)) {
+		t.Fatalf("StackTree commitment does not match ComputeCommitment for shared-prefix keys: %x != %x", bls.ToCompressedG1(got), bls.ToCompressedG1(want))
+	}
+}
+
+func BenchmarkStackTree1MLeaves(b *testing.B) {
+	rand.Seed(time.Now().UnixNano())
+
+	n := 1000000
+	keys, values := randomKVs(n)
+	sort.Sort(&sortableKVs{keys, values})
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		st := NewStackTree(ks, lg1)
+		for j, key := range keys {
+			if err := st.Update(key, values[j]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		st.Commitment()
+	}
+}